@@ -0,0 +1,29 @@
+// Package middleware provides common http.Handler wrappers for gizmo
+// servers.
+package middleware // import "github.com/NYTimes/gizmo/middleware"
+
+import (
+	"net/http"
+
+	"github.com/NYTimes/gizmo/observe"
+	"github.com/pkg/errors"
+)
+
+// RecoverAndReport wraps h so that a panic in h is recovered, reported to
+// Cloud Error Reporting via observe.Report, and turned into a 500 response
+// instead of crashing the server.
+func RecoverAndReport(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err, ok := rec.(error)
+				if !ok {
+					err = errors.Errorf("%v", rec)
+				}
+				observe.Report(r.Context(), err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
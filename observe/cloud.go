@@ -0,0 +1,70 @@
+package observe
+
+import (
+	"context"
+
+	azuremonitor "github.com/microsoft/opentelemetry-exporter-azuremonitor-go/azuremonitorexporter"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newAWSExporter backs BackendAWS. AWS doesn't ship a native OTLP
+// collector of its own, but the AWS Distro for OpenTelemetry (ADOT)
+// collector accepts OTLP and forwards traces to X-Ray and metrics to
+// CloudWatch, so we reuse the OTLP/gRPC pipeline for both and only swap in
+// the X-Ray ID generator so span/trace IDs come out X-Ray compatible.
+func newAWSExporter(opts Options) (Exporter, error) {
+	endpoint := opts.OTLPEndpoint
+	if endpoint == "" {
+		endpoint = defaultADOTEndpoint
+	}
+	traceExp, err := otlptracegrpc.New(context.Background(),
+		otlptracegrpc.WithEndpoint(endpoint),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create aws/x-ray exporter")
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExp),
+		sdktrace.WithResource(newResource(opts)),
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+	)
+
+	metricExp, err := otlpmetricgrpc.New(context.Background(),
+		otlpmetricgrpc.WithEndpoint(endpoint),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create aws/cloudwatch metric exporter")
+	}
+	mp := metric.NewMeterProvider(
+		metric.WithReader(newOCMetricReader(metricExp)),
+		metric.WithResource(newResource(opts)),
+	)
+
+	return &otelBackend{tp: tp, mp: mp, onError: opts.OnError}, nil
+}
+
+// defaultADOTEndpoint is the conventional local address for the ADOT
+// collector sidecar/daemonset.
+const defaultADOTEndpoint = "localhost:4317"
+
+// newAzureExporter backs BackendAzure, sending traces to Azure Monitor
+// (Application Insights). azuremonitorexporter only implements the OTel
+// trace exporter interface, not metrics, so otelBackend.mp stays nil here:
+// RegisterDefaultViews' ochttp/ocgrpc views aren't exported to Azure Monitor
+// until Microsoft ships a metrics exporter for this package.
+func newAzureExporter(opts Options) (Exporter, error) {
+	exp, err := azuremonitor.New(azuremonitor.WithConnectionStringFromEnv())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create azure monitor exporter")
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(newResource(opts)),
+	)
+	return &otelBackend{tp: tp, onError: opts.OnError}, nil
+}
@@ -0,0 +1,94 @@
+package observe
+
+import (
+	"context"
+	"net/http"
+
+	"cloud.google.com/go/errorreporting"
+	"github.com/pkg/errors"
+)
+
+// ErrorReporter wraps a Cloud Error Reporting client so panics and logged
+// errors show up grouped in the Stackdriver console alongside the traces
+// and metrics the rest of this package exports.
+type ErrorReporter struct {
+	client *errorreporting.Client
+}
+
+// currentErrorReporter is set by RegisterAndObserveGCP/WithOptions so the
+// package-level Report and Recover helpers have somewhere to send errors
+// without every caller needing to thread an *ErrorReporter through.
+var currentErrorReporter *ErrorReporter
+
+// newErrorReporter builds an ErrorReporter using the same project,
+// service and version RegisterAndObserveGCP already uses for tracing and
+// metrics.
+func newErrorReporter(projectID, serviceName, serviceVersion string) (*ErrorReporter, error) {
+	client, err := errorreporting.NewClient(context.Background(), projectID, errorreporting.Config{
+		ServiceName:    serviceName,
+		ServiceVersion: serviceVersion,
+		OnError: func(err error) {
+			// avoid infinite recursion: failures reporting errors are
+			// dropped, not reported.
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to initiate error reporting client")
+	}
+	return &ErrorReporter{client: client}, nil
+}
+
+// Report sends err to Cloud Error Reporting, attaching req if given so the
+// console can group by endpoint. It is a no-op if error reporting was
+// never registered (e.g. RegisterAndObserveGCP wasn't called, or the
+// environment isn't GCP-enabled).
+func (r *ErrorReporter) Report(ctx context.Context, err error, req *http.Request) {
+	if r == nil || r.client == nil {
+		return
+	}
+	r.client.Report(errorreporting.Entry{
+		Error: err,
+		Req:   req,
+	})
+}
+
+// Close flushes and releases the underlying Cloud Error Reporting client.
+func (r *ErrorReporter) Close() error {
+	if r == nil || r.client == nil {
+		return nil
+	}
+	return r.client.Close()
+}
+
+// reportingOnError wraps onError (which may be nil) so that Stackdriver
+// exporter failures are themselves sent to Cloud Error Reporting, in
+// addition to whatever the caller's own onError does with them.
+func reportingOnError(onError func(error), reporter *ErrorReporter) func(error) {
+	return func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+		reporter.Report(context.Background(), err, nil)
+	}
+}
+
+// Report sends err to the ErrorReporter registered by RegisterAndObserveGCP,
+// if any. It is safe to call even when error reporting hasn't been set up;
+// the call is simply dropped.
+func Report(ctx context.Context, err error) {
+	currentErrorReporter.Report(ctx, err, nil)
+}
+
+// Recover reports a panic in progress to Cloud Error Reporting and then
+// re-panics so the caller's own recovery (or the default crash behavior)
+// still runs. Use as: defer observe.Recover(ctx)
+func Recover(ctx context.Context) {
+	if rec := recover(); rec != nil {
+		err, ok := rec.(error)
+		if !ok {
+			err = errors.Errorf("%v", rec)
+		}
+		Report(ctx, err)
+		panic(rec)
+	}
+}
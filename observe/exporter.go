@@ -0,0 +1,144 @@
+package observe
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Backend identifies which observability backend an Exporter talks to.
+type Backend string
+
+const (
+	// BackendStackdriver reports traces and metrics to Google Stackdriver.
+	// This is the default, and matches gizmo's historical behavior.
+	BackendStackdriver Backend = "stackdriver"
+	// BackendOTLPGRPC reports traces and metrics to any OpenTelemetry
+	// collector over OTLP/gRPC.
+	BackendOTLPGRPC Backend = "otlp-grpc"
+	// BackendOTLPHTTP reports traces and metrics to any OpenTelemetry
+	// collector over OTLP/HTTP.
+	BackendOTLPHTTP Backend = "otlp-http"
+	// BackendJaeger reports traces to a Jaeger collector.
+	BackendJaeger Backend = "jaeger"
+	// BackendPrometheus exposes metrics for Prometheus to scrape. It does
+	// not export traces.
+	BackendPrometheus Backend = "prometheus"
+	// BackendAWS reports traces to AWS X-Ray and metrics to CloudWatch.
+	BackendAWS Backend = "aws"
+	// BackendAzure reports traces to Azure Monitor. It does not export
+	// metrics; see newAzureExporter for why.
+	BackendAzure Backend = "azure"
+	// BackendNoop discards all traces and metrics. Useful for local
+	// development and tests.
+	BackendNoop Backend = "noop"
+)
+
+// Exporter is the common lifecycle implemented by every observability
+// backend gizmo knows how to register: wire it into the trace and metrics
+// providers, flush whatever is buffered, and shut down cleanly on exit.
+type Exporter interface {
+	// Register wires the exporter into the relevant trace/metrics
+	// providers and starts any background processing required to ship
+	// data to the backend.
+	Register(ctx context.Context) error
+	// Flush forces any buffered spans or metrics to be sent immediately.
+	Flush()
+	// Shutdown flushes and releases any resources held by the exporter.
+	// Servers should call this from their SIGTERM handler so the final
+	// batch of traces/metrics isn't lost.
+	Shutdown(ctx context.Context) error
+}
+
+// Options configures RegisterAndObserve. Fields that don't apply to the
+// selected Backend are ignored.
+type Options struct {
+	// Backend selects the exporter implementation to use. Defaults to
+	// BackendStackdriver when empty, preserving gizmo's original behavior.
+	Backend Backend
+
+	ProjectID      string
+	ServiceName    string
+	ServiceVersion string
+
+	// GCP carries the DisableTracing/DisableStats/DisableProfiler/
+	// TraceSamplingFraction toggles forwarded to the Stackdriver backend
+	// (BackendStackdriver, the default) when set explicitly in code. The
+	// DISABLE_TRACING/DISABLE_STATS/DISABLE_PROFILER/TRACE_SAMPLING_FRACTION
+	// environment variables are honored in addition to these fields - either
+	// one disables a feature - so callers like RegisterAndObserveAuto that
+	// leave GCP unset still get the env-driven behavior RegisterAndObserveGCP
+	// has always had.
+	GCP GCPOptions
+
+	// OTLPEndpoint is the collector address used by BackendOTLPGRPC and
+	// BackendOTLPHTTP, as host:port without a scheme, e.g. "localhost:4317"
+	// or "collector:4318".
+	// Leave it empty to fall back to the otlptracegrpc/otlptracehttp
+	// clients' own default, which reads the standard
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable.
+	OTLPEndpoint string
+
+	// JaegerEndpoint is the collector endpoint used by BackendJaeger, e.g.
+	// "http://localhost:14268/api/traces".
+	JaegerEndpoint string
+
+	// PrometheusAddr is the address the Prometheus-pull exporter listens
+	// on for scrapes, e.g. ":9464". Defaults to ":9464" when empty.
+	PrometheusAddr string
+
+	// OnError, if set, is called whenever the exporter encounters an
+	// error sending data to its backend.
+	OnError func(error)
+}
+
+// RegisterAndObserve composes the trace, metrics and (where supported)
+// profiler exporters for opts.Backend and registers them with the
+// OpenTelemetry SDK providers, bridging OpenCensus instrumentation so
+// existing callers of the trace/view packages keep working unchanged. It
+// returns a Shutdown func that callers in server/ should invoke from their
+// SIGTERM handler to flush the last batch before the process exits.
+func RegisterAndObserve(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	if SkipObserve() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exp, err := NewExporter(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build observe exporter")
+	}
+	if err := exp.Register(ctx); err != nil {
+		return nil, errors.Wrap(err, "unable to register observe exporter")
+	}
+	return exp.Shutdown, nil
+}
+
+// NewExporter builds the Exporter for the backend named in opts.Backend.
+func NewExporter(opts Options) (Exporter, error) {
+	switch opts.Backend {
+	case BackendStackdriver, "":
+		return newStackdriverBackend(opts)
+	case BackendOTLPGRPC:
+		return newOTLPGRPCExporter(opts)
+	case BackendOTLPHTTP:
+		return newOTLPHTTPExporter(opts)
+	case BackendJaeger:
+		return newJaegerExporter(opts)
+	case BackendPrometheus:
+		return newPrometheusExporter(opts)
+	case BackendAWS:
+		return newAWSExporter(opts)
+	case BackendAzure:
+		return newAzureExporter(opts)
+	case BackendNoop:
+		return noopExporter{}, nil
+	default:
+		return nil, errors.Errorf("observe: unknown backend %q", opts.Backend)
+	}
+}
+
+// noopExporter discards all traces and metrics. It backs BackendNoop.
+type noopExporter struct{}
+
+func (noopExporter) Register(ctx context.Context) error { return nil }
+func (noopExporter) Flush()                             {}
+func (noopExporter) Shutdown(ctx context.Context) error { return nil }
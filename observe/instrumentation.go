@@ -0,0 +1,70 @@
+package observe
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ocgrpc"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/stats/view"
+	"google.golang.org/grpc"
+)
+
+// HTTPHandler wraps h with an ochttp.Handler so incoming requests are
+// traced and measured via OpenCensus, using B3 (Zipkin-style) header
+// propagation rather than Stackdriver's X-Cloud-Trace-Context. Use with your
+// server/ handler chain instead of wiring up ochttp yourself.
+func HTTPHandler(h http.Handler, opts ...func(*ochttp.Handler)) http.Handler {
+	och := &ochttp.Handler{
+		Handler:     h,
+		Propagation: &b3.HTTPFormat{},
+	}
+	for _, opt := range opts {
+		opt(och)
+	}
+	return och
+}
+
+// HTTPTransport wraps base (or http.DefaultTransport if nil) with an
+// ochttp.Transport so outgoing requests are traced and measured, again
+// using B3 propagation.
+func HTTPTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &ochttp.Transport{
+		Base:        base,
+		Propagation: &b3.HTTPFormat{},
+	}
+}
+
+// GRPCServerOptions returns the grpc.ServerOption needed to trace and
+// measure incoming RPCs with OpenCensus. Pass it to grpc.NewServer
+// alongside any other options your service needs.
+func GRPCServerOptions() grpc.ServerOption {
+	return grpc.StatsHandler(&ocgrpc.ServerHandler{})
+}
+
+// GRPCDialOptions returns the grpc.DialOption needed to trace and measure
+// outgoing RPCs with OpenCensus. Pass it to grpc.Dial alongside any other
+// options your client needs.
+func GRPCDialOptions() grpc.DialOption {
+	return grpc.WithStatsHandler(&ocgrpc.ClientHandler{})
+}
+
+// RegisterDefaultViews registers the standard ochttp and ocgrpc
+// client/server views - started/completed RPCs, roundtrip latency, and
+// sent/received bytes - so services using HTTPHandler/HTTPTransport and
+// GRPCServerOptions/GRPCDialOptions get useful Stackdriver dashboards
+// without any further setup.
+func RegisterDefaultViews() error {
+	views := append(append(append(append(
+		[]*view.View{},
+		ochttp.DefaultServerViews...),
+		ochttp.DefaultClientViews...),
+		ocgrpc.DefaultServerViews...),
+		ocgrpc.DefaultClientViews...)
+
+	return errors.Wrap(view.Register(views...), "unable to register default ochttp/ocgrpc views")
+}
@@ -1,11 +1,15 @@
-// Package observe provides functions
-// that help with setting tracing/metrics
-// in cloud providers, mainly GCP.
+// Package observe provides functions that help with setting tracing/metrics
+// in cloud providers. Stackdriver/GCP remains the default via
+// RegisterAndObserveGCP, but RegisterAndObserve supports pluggable
+// Exporter backends (OTLP, Jaeger, Prometheus, or a no-op) for teams
+// running elsewhere or migrating to OpenTelemetry.
 package observe // import "github.com/NYTimes/gizmo/observe"
 
 import (
 	"context"
 	"os"
+	"strconv"
+	"time"
 
 	"cloud.google.com/go/profiler"
 	traceapi "cloud.google.com/go/trace/apiv2"
@@ -16,6 +20,7 @@ import (
 	"go.opencensus.io/trace"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
 // RegisterAndObserveGCP will initiate and register Stackdriver profiling and tracing and
@@ -24,6 +29,37 @@ import (
 // function. Tracing and metrics are enabled via OpenCensus exporters. See the OpenCensus
 // documentation for instructions for registering additional spans and metrics.
 func RegisterAndObserveGCP(onError func(error)) error {
+	return RegisterAndObserveGCPWithOptions(onError, GCPOptions{
+		DisableTracing:        DisableTracing(),
+		DisableStats:          DisableStats(),
+		DisableProfiler:       DisableProfiler(),
+		TraceSamplingFraction: TraceSamplingFraction(),
+	})
+}
+
+// GCPOptions controls which pieces of RegisterAndObserveGCP are enabled.
+// Use RegisterAndObserveGCPWithOptions to pass these in code; RegisterAndObserveGCP
+// derives the same options from the DISABLE_TRACING, DISABLE_STATS,
+// DISABLE_PROFILER and TRACE_SAMPLING_FRACTION environment variables.
+type GCPOptions struct {
+	// DisableTracing skips registering the Stackdriver trace exporter.
+	DisableTracing bool
+	// DisableStats skips registering the Stackdriver metrics exporter.
+	DisableStats bool
+	// DisableProfiler skips starting the Stackdriver profiler.
+	DisableProfiler bool
+	// TraceSamplingFraction sets the fraction of requests traced via
+	// trace.ProbabilitySampler. Zero leaves OpenCensus's default sampler
+	// in place.
+	TraceSamplingFraction float64
+}
+
+// RegisterAndObserveGCPWithOptions behaves like RegisterAndObserveGCP but
+// takes its toggles as a GCPOptions value instead of reading them from the
+// environment. This lets programs that want to disable tracing, stats or
+// the profiler selectively - App Engine flex, Cloud Run, GKE, etc. - do so
+// without shelling out environment variables.
+func RegisterAndObserveGCPWithOptions(onError func(error), opts GCPOptions) error {
 	if SkipObserve() {
 		return nil
 	}
@@ -33,14 +69,24 @@ func RegisterAndObserveGCP(onError func(error)) error {
 
 	projectID, svcName, svcVersion := GetServiceInfo()
 
-	exp, err := NewStackdriverExporter(projectID, onError)
-	if err != nil {
-		return errors.Wrap(err, "unable to initiate error tracing exporter")
+	if reporter, err := newErrorReporter(projectID, svcName, svcVersion); err == nil {
+		currentErrorReporter = reporter
+		onError = reportingOnError(onError, reporter)
+	}
+
+	if opts.TraceSamplingFraction > 0 {
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(opts.TraceSamplingFraction)})
+	}
+
+	if err := registerStackdriver(projectID, onError, !opts.DisableTracing, !opts.DisableStats); err != nil {
+		return err
 	}
-	trace.RegisterExporter(exp)
-	view.RegisterExporter(exp)
 
-	err = profiler.Start(profiler.Config{
+	if opts.DisableProfiler {
+		return nil
+	}
+
+	err := profiler.Start(profiler.Config{
 		ProjectID:      projectID,
 		Service:        svcName,
 		ServiceVersion: svcVersion,
@@ -48,16 +94,129 @@ func RegisterAndObserveGCP(onError func(error)) error {
 	return errors.Wrap(err, "unable to initiate profiling client")
 }
 
+// DisableTracing checks if the DISABLE_TRACING environment variable has
+// been populated, skipping Stackdriver trace exporter registration.
+func DisableTracing() bool {
+	return os.Getenv("DISABLE_TRACING") != ""
+}
+
+// DisableStats checks if the DISABLE_STATS environment variable has been
+// populated, skipping Stackdriver metrics exporter registration.
+func DisableStats() bool {
+	return os.Getenv("DISABLE_STATS") != ""
+}
+
+// DisableProfiler checks if the DISABLE_PROFILER environment variable has
+// been populated, skipping Stackdriver profiler startup.
+func DisableProfiler() bool {
+	return os.Getenv("DISABLE_PROFILER") != ""
+}
+
+// TraceSamplingFraction reads the TRACE_SAMPLING_FRACTION environment
+// variable and returns it as a float64 for use with
+// trace.ProbabilitySampler. Returns 0 if unset or unparseable, which
+// leaves OpenCensus's default sampler in place.
+func TraceSamplingFraction() float64 {
+	f, err := strconv.ParseFloat(os.Getenv("TRACE_SAMPLING_FRACTION"), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// currentSDExporter is tracked so Flush can be called on shutdown without
+// requiring every caller to hang onto the *stackdriver.Exporter returned
+// by NewStackdriverExporter.
+var currentSDExporter *stackdriver.Exporter
+
+// registerStackdriver builds and registers the Stackdriver trace/metrics
+// exporter, shared by RegisterAndObserveGCPWithOptions and the
+// stackdriverBackend Exporter implementation. enableTracing/enableStats
+// let callers skip either half of the exporter independently.
+func registerStackdriver(projectID string, onError func(error), enableTracing, enableStats bool) error {
+	exp, err := NewStackdriverExporter(projectID, onError)
+	if err != nil {
+		return errors.Wrap(err, "unable to initiate error tracing exporter")
+	}
+	if exp == nil {
+		return nil
+	}
+	if enableTracing {
+		trace.RegisterExporter(exp)
+	}
+	if enableStats {
+		view.RegisterExporter(exp)
+	}
+	currentSDExporter = exp
+	return nil
+}
+
+// flushStackdriver flushes the Stackdriver exporter registered by
+// registerStackdriver, if any.
+func flushStackdriver() {
+	if currentSDExporter != nil {
+		currentSDExporter.Flush()
+	}
+}
+
 // NewStackdriverExporter will return the tracing and metrics through
 // the stack driver exporter, if exists in the underlying platform.
 // If exporter is registered, it returns the exporter so you can register
 // it and ensure to call Flush on termination.
 func NewStackdriverExporter(projectID string, onErr func(error)) (*stackdriver.Exporter, error) {
+	return NewStackdriverExporterWithConfig(projectID, onErr, StackdriverConfig{})
+}
+
+// StackdriverConfig carries the knobs NewStackdriverExporterWithConfig
+// needs beyond a bare project ID: how often to report metrics, and how to
+// override the monitored resource Stackdriver attributes data to when
+// autodetection isn't good enough (or returns nil, e.g. on a VM outside
+// GCP).
+type StackdriverConfig struct {
+	// ReportingInterval is passed to view.SetReportingPeriod when it's set
+	// above zero. Stackdriver's write-quota guidance recommends at least
+	// 60s. Leave it zero to keep whatever reporting period OpenCensus
+	// already has in effect - view.SetReportingPeriod is process-wide
+	// state, so setting this affects every other view exporter registered
+	// in the process, not just this one.
+	ReportingInterval time.Duration
+	// MonitoredResource overrides monitoredresource.Autodetect, e.g. for a
+	// Cloud Run revision label or a k8s_container with custom labels.
+	MonitoredResource monitoredresource.Interface
+	// Resource is used in place of MonitoredResource when callers need to
+	// build the pb.MonitoredResource by hand, e.g. generic_task for VMs
+	// outside GCP where autodetect returns nil.
+	Resource *monitoredres.MonitoredResource
+	// MetricPrefix is prepended to exported metric names.
+	MetricPrefix string
+	// BundleDelayThreshold and BundleCountThreshold tune how eagerly the
+	// exporter bundles spans/metrics before sending; see the stackdriver
+	// package docs for their defaults.
+	BundleDelayThreshold time.Duration
+	BundleCountThreshold int
+}
+
+// NewStackdriverExporterWithConfig behaves like NewStackdriverExporter but
+// takes a StackdriverConfig for callers that need a non-default reporting
+// interval or monitored resource, such as services running outside the
+// resources monitoredresource.Autodetect knows how to find.
+func NewStackdriverExporterWithConfig(projectID string, onErr func(error), cfg StackdriverConfig) (*stackdriver.Exporter, error) {
 	_, svcName, svcVersion := GetServiceInfo()
-	opts := getSDOpts(projectID, svcName, svcVersion, onErr)
+	opts := getSDOpts(projectID, svcName, svcVersion, onErr, cfg)
 	if opts == nil {
 		return nil, nil
 	}
+
+	// Only override OpenCensus's global reporting period when the caller
+	// explicitly asked for one. NewStackdriverExporter passes a zero-value
+	// StackdriverConfig, and since view.SetReportingPeriod affects every
+	// registered view exporter process-wide, calling it unconditionally
+	// here would silently reset any reporting period set elsewhere in the
+	// process back to OpenCensus's 10s default.
+	if cfg.ReportingInterval > 0 {
+		view.SetReportingPeriod(cfg.ReportingInterval)
+	}
+
 	return stackdriver.NewExporter(*opts)
 }
 
@@ -97,26 +256,32 @@ func GetServiceInfo() (projectID, service, version string) {
 
 // getSDOpts returns Stack Driver Options that you can pass directly
 // to the OpenCensus exporter or other libraries.
-func getSDOpts(projectID, service, version string, onErr func(err error)) *stackdriver.Options {
-	var mr monitoredresource.Interface
+func getSDOpts(projectID, service, version string, onErr func(err error), cfg StackdriverConfig) *stackdriver.Options {
+	mr := cfg.MonitoredResource
 
 	// this is so that you can export views from your local server up to SD if you wish
 	creds, err := google.FindDefaultCredentials(context.Background(), traceapi.DefaultAuthScopes()...)
 	if err != nil {
 		return nil
 	}
-	canExport := IsGAE()
-	if m := monitoredresource.Autodetect(); m != nil {
-		mr = m
-		canExport = true
+	canExport := IsGAE() || mr != nil || cfg.Resource != nil
+	if mr == nil {
+		if m := monitoredresource.Autodetect(); m != nil {
+			mr = m
+			canExport = true
+		}
 	}
 	if !canExport {
 		return nil
 	}
 
 	return &stackdriver.Options{
-		ProjectID:         projectID,
-		MonitoredResource: mr,
+		ProjectID:            projectID,
+		MonitoredResource:    mr,
+		Resource:             cfg.Resource,
+		MetricPrefix:         cfg.MetricPrefix,
+		BundleDelayThreshold: cfg.BundleDelayThreshold,
+		BundleCountThreshold: cfg.BundleCountThreshold,
 		MonitoringClientOptions: []option.ClientOption{
 			option.WithCredentials(creds),
 		},
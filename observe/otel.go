@@ -0,0 +1,206 @@
+package observe
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/bridge/opencensus"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// defaultPrometheusAddr is used when Options.PrometheusAddr is empty.
+const defaultPrometheusAddr = ":9464"
+
+// otelBackend is the common shape behind every OpenTelemetry-based
+// Exporter: a trace provider, an optional metric provider, and an
+// OpenCensus bridge exporter so code still calling trace.RegisterExporter
+// or view.RegisterExporter (including gizmo's own middleware) keeps
+// shipping data through the same pipeline. tp is nil for metrics-only
+// backends (Prometheus), so Register/Flush/Shutdown all guard it.
+type otelBackend struct {
+	tp      *sdktrace.TracerProvider
+	mp      *metric.MeterProvider
+	promSrv *http.Server
+	onError func(error)
+}
+
+func newResource(opts Options) *resource.Resource {
+	r, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceNameKey.String(opts.ServiceName),
+		semconv.ServiceVersionKey.String(opts.ServiceVersion),
+	))
+	if err != nil {
+		return resource.Default()
+	}
+	return r
+}
+
+func (b *otelBackend) Register(ctx context.Context) error {
+	// otel.SetErrorHandler is process-wide, but it's the only hook the
+	// OTel SDK gives us for opts.OnError, and every OTel-based backend
+	// wants the same behavior, so one call here covers all of them.
+	if b.onError != nil {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(b.onError))
+	}
+	if b.tp != nil {
+		otel.SetTracerProvider(b.tp)
+		bridge := opencensus.NewTracer(b.tp.Tracer("go.opencensus.io"))
+		trace.DefaultTracer = bridge
+	}
+	if b.mp != nil {
+		otel.SetMeterProvider(b.mp)
+	}
+	return nil
+}
+
+// newOCMetricReader builds an OTel metrics reader that pulls from
+// OpenCensus's stats/view registry via the bridge's MetricProducer, so the
+// ochttp/ocgrpc views RegisterDefaultViews registers (and any custom views
+// gizmo callers register) are exported through exp on the same cadence as
+// everything else sent to exp.
+func newOCMetricReader(exp metric.Exporter) metric.Reader {
+	return metric.NewPeriodicReader(exp, metric.WithProducer(opencensus.NewMetricProducer()))
+}
+
+func (b *otelBackend) Flush() {
+	if b.tp != nil {
+		_ = b.tp.ForceFlush(context.Background())
+	}
+	if b.mp != nil {
+		_ = b.mp.ForceFlush(context.Background())
+	}
+}
+
+func (b *otelBackend) Shutdown(ctx context.Context) error {
+	b.Flush()
+	if b.promSrv != nil {
+		if err := b.promSrv.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	if b.mp != nil {
+		if err := b.mp.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	if b.tp == nil {
+		return nil
+	}
+	return b.tp.Shutdown(ctx)
+}
+
+func newOTLPGRPCExporter(opts Options) (Exporter, error) {
+	var traceOpts []otlptracegrpc.Option
+	var metricOpts []otlpmetricgrpc.Option
+	if opts.OTLPEndpoint != "" {
+		traceOpts = append(traceOpts, otlptracegrpc.WithEndpoint(opts.OTLPEndpoint))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpoint(opts.OTLPEndpoint))
+	}
+
+	traceExp, err := otlptracegrpc.New(context.Background(), traceOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create otlp/grpc trace exporter")
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExp),
+		sdktrace.WithResource(newResource(opts)),
+	)
+
+	metricExp, err := otlpmetricgrpc.New(context.Background(), metricOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create otlp/grpc metric exporter")
+	}
+	mp := metric.NewMeterProvider(
+		metric.WithReader(newOCMetricReader(metricExp)),
+		metric.WithResource(newResource(opts)),
+	)
+
+	return &otelBackend{tp: tp, mp: mp, onError: opts.OnError}, nil
+}
+
+func newOTLPHTTPExporter(opts Options) (Exporter, error) {
+	var traceOpts []otlptracehttp.Option
+	var metricOpts []otlpmetrichttp.Option
+	if opts.OTLPEndpoint != "" {
+		traceOpts = append(traceOpts, otlptracehttp.WithEndpoint(opts.OTLPEndpoint))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithEndpoint(opts.OTLPEndpoint))
+	}
+
+	traceExp, err := otlptracehttp.New(context.Background(), traceOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create otlp/http trace exporter")
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExp),
+		sdktrace.WithResource(newResource(opts)),
+	)
+
+	metricExp, err := otlpmetrichttp.New(context.Background(), metricOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create otlp/http metric exporter")
+	}
+	mp := metric.NewMeterProvider(
+		metric.WithReader(newOCMetricReader(metricExp)),
+		metric.WithResource(newResource(opts)),
+	)
+
+	return &otelBackend{tp: tp, mp: mp, onError: opts.OnError}, nil
+}
+
+// newJaegerExporter backs BackendJaeger. The Jaeger collector protocol only
+// carries spans, so unlike the other OTel-based backends this one leaves
+// otelBackend.mp nil: there is no metrics pipeline to wire up, and
+// RegisterDefaultViews' ochttp/ocgrpc views simply aren't exported when
+// BackendJaeger is selected.
+func newJaegerExporter(opts Options) (Exporter, error) {
+	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(
+		jaeger.WithEndpoint(opts.JaegerEndpoint),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create jaeger exporter")
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(newResource(opts)),
+	)
+	return &otelBackend{tp: tp, onError: opts.OnError}, nil
+}
+
+func newPrometheusExporter(opts Options) (Exporter, error) {
+	exp, err := prometheus.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create prometheus exporter")
+	}
+	mp := metric.NewMeterProvider(
+		metric.WithReader(exp),
+		metric.WithResource(newResource(opts)),
+	)
+
+	addr := opts.PrometheusAddr
+	if addr == "" {
+		addr = defaultPrometheusAddr
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed && opts.OnError != nil {
+			opts.OnError(errors.Wrap(err, "prometheus scrape endpoint stopped"))
+		}
+	}()
+
+	return &otelBackend{mp: mp, promSrv: srv, onError: opts.OnError}, nil
+}
@@ -0,0 +1,231 @@
+package observe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// metadataClient is used for the AWS and Azure metadata probes below. Both
+// endpoints are link-local and unreachable off-platform, so a short
+// timeout keeps detection from stalling startup.
+var metadataClient = &http.Client{Timeout: 250 * time.Millisecond}
+
+const (
+	awsInstanceIDURL       = "http://169.254.169.254/latest/meta-data/instance-id"
+	awsAvailabilityZoneURL = "http://169.254.169.254/latest/meta-data/placement/availability-zone"
+	azureMetadataURL       = "http://169.254.169.254/metadata/instance?api-version=2021-02-01"
+	k8sServiceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// awsIdentity holds the fields GetPlatformInfo reads back from the AWS
+// instance metadata service.
+type awsIdentity struct {
+	instanceID       string
+	availabilityZone string
+}
+
+// fetchAWSIdentity fetches the instance ID and availability zone from the
+// AWS instance metadata service. It returns its zero value (and ok=false)
+// on any failure, including when the service is unreachable - callers
+// should treat that as "no AWS metadata available" rather than an error.
+func fetchAWSIdentity() (id awsIdentity, ok bool) {
+	instanceID, err := fetchMetadataText(awsInstanceIDURL, nil)
+	if err != nil {
+		return awsIdentity{}, false
+	}
+	// availabilityZone is best-effort; instance ID is still useful without it.
+	az, _ := fetchMetadataText(awsAvailabilityZoneURL, nil)
+	return awsIdentity{instanceID: instanceID, availabilityZone: az}, true
+}
+
+// azureIdentity holds the fields GetPlatformInfo reads back from the Azure
+// Instance Metadata Service's "compute" document.
+type azureIdentity struct {
+	VMID     string `json:"vmId"`
+	Location string `json:"location"`
+}
+
+// azureMetadataDoc mirrors the subset of the IMDS instance document
+// GetPlatformInfo cares about.
+type azureMetadataDoc struct {
+	Compute azureIdentity `json:"compute"`
+}
+
+// fetchAzureIdentity fetches the compute document from the Azure Instance
+// Metadata Service. It returns its zero value (and ok=false) on any
+// failure, including when the service is unreachable.
+func fetchAzureIdentity() (id azureIdentity, ok bool) {
+	body, err := fetchMetadataText(azureMetadataURL, map[string]string{"Metadata": "true"})
+	if err != nil {
+		return azureIdentity{}, false
+	}
+	var doc azureMetadataDoc
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return azureIdentity{}, false
+	}
+	return doc.Compute, true
+}
+
+// fetchMetadataText issues a GET to url with headers and returns the
+// response body as a string, failing unless the response is 200 OK.
+func fetchMetadataText(url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("metadata request to %s returned %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// IsAWS tells you whether your program is running on EC2 or ECS, via the
+// AWS instance metadata service (or the ECS container metadata URI env
+// var, which EC2 metadata doesn't see from inside a task).
+func IsAWS() bool {
+	if os.Getenv("ECS_CONTAINER_METADATA_URI") != "" || os.Getenv("ECS_CONTAINER_METADATA_URI_V4") != "" {
+		return true
+	}
+	_, ok := fetchAWSIdentity()
+	return ok
+}
+
+// IsAzure tells you whether your program is running on Azure, via the
+// Azure Instance Metadata Service.
+func IsAzure() bool {
+	_, ok := fetchAzureIdentity()
+	return ok
+}
+
+// IsKubernetes tells you whether your program is running inside a
+// Kubernetes pod, via the in-cluster service account token every pod is
+// projected.
+func IsKubernetes() bool {
+	_, err := os.Stat(k8sServiceAccountToken)
+	return err == nil
+}
+
+// Provider identifies the cloud or orchestration platform GetPlatformInfo
+// detected.
+type Provider string
+
+const (
+	ProviderGCP        Provider = "gcp"
+	ProviderAWS        Provider = "aws"
+	ProviderAzure      Provider = "azure"
+	ProviderKubernetes Provider = "kubernetes"
+	ProviderUnknown    Provider = "unknown"
+)
+
+// PlatformInfo normalizes the handful of facts RegisterAndObserveAuto and
+// its backends need regardless of where the program is running.
+type PlatformInfo struct {
+	Provider Provider
+	// Region is best-effort; it's left empty where detection doesn't
+	// cheaply provide one (e.g. Kubernetes, where "region" is cluster-
+	// dependent rather than something the pod can discover).
+	Region      string
+	InstanceID  string
+	ServiceName string
+	Version     string
+}
+
+// GetPlatformInfo detects which platform the program is running on -
+// checking GCP, then AWS, then Azure, then plain Kubernetes - and returns
+// a normalized PlatformInfo suitable for use as trace attributes or
+// monitored-resource labels. ServiceName and Version come from
+// GetServiceInfo. Region and InstanceID are populated from the AWS/Azure
+// metadata services on those platforms when the service answers (an ECS
+// task detected only by its metadata env vars leaves them empty); they're
+// left empty on GCP (use
+// monitoredresource.Autodetect instead), Kubernetes, and Unknown.
+func GetPlatformInfo() PlatformInfo {
+	_, svcName, svcVersion := GetServiceInfo()
+	info := PlatformInfo{ServiceName: svcName, Version: svcVersion}
+
+	if IsGCPEnabled() {
+		info.Provider = ProviderGCP
+		return info
+	}
+	if id, ok := fetchAWSIdentity(); ok || os.Getenv("ECS_CONTAINER_METADATA_URI") != "" || os.Getenv("ECS_CONTAINER_METADATA_URI_V4") != "" {
+		info.Provider = ProviderAWS
+		info.InstanceID = id.instanceID
+		info.Region = awsRegionFromZone(id.availabilityZone)
+		return info
+	}
+	if id, ok := fetchAzureIdentity(); ok {
+		info.Provider = ProviderAzure
+		info.InstanceID = id.VMID
+		info.Region = id.Location
+		return info
+	}
+	if IsKubernetes() {
+		info.Provider = ProviderKubernetes
+		return info
+	}
+	info.Provider = ProviderUnknown
+	return info
+}
+
+// awsRegionFromZone trims the trailing availability-zone letter, e.g.
+// "us-east-1a" becomes "us-east-1". Returns zone unchanged if it doesn't
+// look like an availability zone.
+func awsRegionFromZone(zone string) string {
+	if zone == "" {
+		return ""
+	}
+	last := zone[len(zone)-1]
+	if last < 'a' || last > 'z' {
+		return zone
+	}
+	return zone[:len(zone)-1]
+}
+
+// RegisterAndObserveAuto detects the platform the program is running on
+// and registers the matching Exporter: Stackdriver on GCP, CloudWatch/
+// X-Ray on AWS, Azure Monitor on Azure, or OTLP otherwise. Of the detected
+// PlatformInfo only ServiceName and Version are passed on, as
+// Options.ServiceName/ServiceVersion; Provider, Region and InstanceID are
+// not attached to the exporter's resource attributes or monitored-resource
+// labels. It returns a Shutdown func the caller should
+// invoke from its SIGTERM handler, same as RegisterAndObserve.
+func RegisterAndObserveAuto(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	info := GetPlatformInfo()
+
+	opts := Options{
+		ProjectID:      GoogleProjectID(),
+		ServiceName:    info.ServiceName,
+		ServiceVersion: info.Version,
+	}
+
+	switch info.Provider {
+	case ProviderGCP:
+		opts.Backend = BackendStackdriver
+	case ProviderAWS:
+		opts.Backend = BackendAWS
+	case ProviderAzure:
+		opts.Backend = BackendAzure
+	default:
+		opts.Backend = BackendOTLPGRPC
+	}
+
+	return RegisterAndObserve(ctx, opts)
+}
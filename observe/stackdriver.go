@@ -0,0 +1,78 @@
+package observe
+
+import (
+	"context"
+
+	"cloud.google.com/go/profiler"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+)
+
+// stackdriverBackend adapts the existing Stackdriver/OpenCensus exporter
+// and profiler startup to the Exporter interface, preserving
+// RegisterAndObserveGCP's original behavior - including its
+// GIZMO_SKIP_OBSERVE/DISABLE_*/TRACE_SAMPLING_FRACTION toggles and Cloud
+// Error Reporting integration - under the new pluggable backend model.
+type stackdriverBackend struct {
+	opts     Options
+	reporter *ErrorReporter
+}
+
+func newStackdriverBackend(opts Options) (Exporter, error) {
+	return &stackdriverBackend{opts: opts}, nil
+}
+
+func (b *stackdriverBackend) Register(ctx context.Context) error {
+	if SkipObserve() {
+		return nil
+	}
+
+	disableTracing := b.opts.GCP.DisableTracing || DisableTracing()
+	disableStats := b.opts.GCP.DisableStats || DisableStats()
+	disableProfiler := b.opts.GCP.DisableProfiler || DisableProfiler()
+	samplingFraction := b.opts.GCP.TraceSamplingFraction
+	if samplingFraction == 0 {
+		samplingFraction = TraceSamplingFraction()
+	}
+
+	onErr := b.opts.OnError
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if reporter, err := newErrorReporter(b.opts.ProjectID, b.opts.ServiceName, b.opts.ServiceVersion); err == nil {
+		b.reporter = reporter
+		currentErrorReporter = reporter
+		onErr = reportingOnError(onErr, reporter)
+	}
+
+	if samplingFraction > 0 {
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(samplingFraction)})
+	}
+
+	if err := registerStackdriver(b.opts.ProjectID, onErr, !disableTracing, !disableStats); err != nil {
+		return err
+	}
+
+	if disableProfiler {
+		return nil
+	}
+
+	err := profiler.Start(profiler.Config{
+		ProjectID:      b.opts.ProjectID,
+		Service:        b.opts.ServiceName,
+		ServiceVersion: b.opts.ServiceVersion,
+	})
+	return errors.Wrap(err, "unable to initiate profiling client")
+}
+
+func (b *stackdriverBackend) Flush() {
+	flushStackdriver()
+}
+
+func (b *stackdriverBackend) Shutdown(ctx context.Context) error {
+	b.Flush()
+	if b.reporter != nil {
+		return b.reporter.Close()
+	}
+	return nil
+}